@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +20,14 @@ import (
 
 const driverName = "inmemory"
 
+// defaultMaxThreads is the default value used for DriverParameters.MaxThreads
+// and minThreads is the lowest value the driver will accept, mirroring the
+// filesystem driver's concurrency floor.
+const (
+	defaultMaxThreads = uint64(100)
+	minThreads        = uint64(25)
+)
+
 func init() {
 	factory.Register(driverName, &inMemoryDriverFactory{})
 }
@@ -26,12 +36,177 @@ func init() {
 type inMemoryDriverFactory struct{}
 
 func (factory *inMemoryDriverFactory) Create(ctx context.Context, parameters map[string]interface{}) (storagedriver.StorageDriver, error) {
-	return New(), nil
+	return FromParameters(parameters)
+}
+
+// DriverParameters is the set of configuration parameters accepted by the
+// inmemory driver's FromParameters and New constructors.
+type DriverParameters struct {
+	// MaxThreads limits the number of concurrent operations the driver will
+	// perform, as enforced by base.NewRegulator. It is floored at
+	// minThreads.
+	MaxThreads uint64
+
+	// MaxBytes and MaxEntries cap the total content size and count of blobs
+	// the driver will hold before evicting the least-recently-used ones. A
+	// value of 0 means unbounded.
+	MaxBytes   int64
+	MaxEntries int64
+
+	// SnapshotPath, if non-empty, is a file the driver loads its tree from
+	// at construction time and persists it to afterward, letting an
+	// in-memory registry survive process restarts. SnapshotInterval, if
+	// positive, additionally flushes a snapshot on that schedule via a
+	// background goroutine; otherwise the tree is only written to
+	// SnapshotPath on Close. (Snapshot writes to the io.Writer it is given,
+	// never to SnapshotPath.)
+	SnapshotPath     string
+	SnapshotInterval time.Duration
+
+	// RedirectBaseURL and RedirectSecret, if both set, turn on RedirectURL:
+	// it returns an HMAC-signed, time-limited URL under RedirectBaseURL
+	// that Handler validates and serves the blob from.
+	RedirectBaseURL string
+	RedirectSecret  string
+}
+
+// FromParameters constructs a new Driver from a map of configuration
+// parameters, as supplied in a registry configuration file. Recognized
+// parameters are "maxthreads", "maxbytes", "maxentries", "snapshotpath",
+// "snapshotinterval", "redirectbaseurl", and "redirectsecret".
+func FromParameters(parameters map[string]interface{}) (*Driver, error) {
+	var params DriverParameters
+
+	maxThreads, err := base.GetLimitFromParameter(parameters["maxthreads"], minThreads, defaultMaxThreads)
+	if err != nil {
+		return nil, fmt.Errorf("maxthreads config error: %s", err.Error())
+	}
+	params.MaxThreads = maxThreads
+
+	maxBytes, err := parseCapacityParam(parameters, "maxbytes")
+	if err != nil {
+		return nil, err
+	}
+	params.MaxBytes = maxBytes
+
+	maxEntries, err := parseCapacityParam(parameters, "maxentries")
+	if err != nil {
+		return nil, err
+	}
+	params.MaxEntries = maxEntries
+
+	if v, ok := parameters["snapshotpath"]; ok && v != nil {
+		params.SnapshotPath = fmt.Sprint(v)
+	}
+
+	snapshotInterval, err := parseDurationParam(parameters, "snapshotinterval")
+	if err != nil {
+		return nil, err
+	}
+	params.SnapshotInterval = snapshotInterval
+
+	if v, ok := parameters["redirectbaseurl"]; ok && v != nil {
+		params.RedirectBaseURL = fmt.Sprint(v)
+	}
+	if v, ok := parameters["redirectsecret"]; ok && v != nil {
+		params.RedirectSecret = fmt.Sprint(v)
+	}
+
+	return New(params), nil
 }
 
+// parseDurationParam extracts a time.Duration named key from parameters. A
+// missing or nil value returns a zero duration.
+func parseDurationParam(parameters map[string]interface{}, key string) (time.Duration, error) {
+	v, ok := parameters[key]
+	if !ok || v == nil {
+		return 0, nil
+	}
+
+	switch v := v.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("%s parameter must be a duration: %v", key, err)
+		}
+		return d, nil
+	case time.Duration:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("%s parameter must be a duration, got %T", key, v)
+	}
+}
+
+// parseCapacityParam extracts a non-negative int64 capacity limit named key
+// from parameters. A missing or nil value means "unbounded" (0).
+func parseCapacityParam(parameters map[string]interface{}, key string) (int64, error) {
+	v, ok := parameters[key]
+	if !ok || v == nil {
+		return 0, nil
+	}
+
+	var limit int64
+	switch v := v.(type) {
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s parameter must be an integer: %v", key, err)
+		}
+		limit = parsed
+	case int:
+		limit = int64(v)
+	case int64:
+		limit = v
+	case uint64:
+		limit = int64(v)
+	default:
+		return 0, fmt.Errorf("%s parameter must be an integer, got %T", key, v)
+	}
+
+	if limit < 0 {
+		return 0, fmt.Errorf("%s parameter must not be negative, got %d", key, limit)
+	}
+	return limit, nil
+}
+
+// driver is the raw, unregulated implementation of storagedriver.StorageDriver.
+// Driver composes it behind a base.Regulator to enforce MaxThreads.
 type driver struct {
 	root  *dir
 	mutex sync.RWMutex
+
+	// maxbytes and maxentries cap the total size and count of blobs the
+	// driver will hold before evicting the least-recently-used ones. A
+	// value of 0 means unbounded.
+	maxbytes   int64
+	maxentries int64
+	totalBytes int64
+
+	// lru tracks blob paths in least-recently-used order, guarded
+	// independently of mutex so that reads (which only take mutex for
+	// reading) can still update recency.
+	lruMu sync.Mutex
+	lru   *lruList
+
+	// inflight holds the normalized paths of blobs with an open,
+	// uncommitted writer. Eviction skips these.
+	inflightMu sync.Mutex
+	inflight   map[string]struct{}
+
+	// snapshotPath and snapshotInterval configure optional persistence; see
+	// DriverParameters. stopSnapshot/snapshotWG coordinate shutting down the
+	// background flush goroutine started for a positive snapshotInterval.
+	snapshotPath     string
+	snapshotInterval time.Duration
+	stopSnapshot     chan struct{}
+	snapshotWG       sync.WaitGroup
+	closeOnce        sync.Once
+
+	// redirectBaseURL and redirectSecret configure RedirectURL/Handler; see
+	// DriverParameters. RedirectURL is disabled (returns "") unless both
+	// are set.
+	redirectBaseURL string
+	redirectSecret  []byte
 }
 
 // baseEmbed allows us to hide the Base embed.
@@ -43,25 +218,76 @@ type baseEmbed struct {
 // Intended solely for example and testing purposes.
 type Driver struct {
 	baseEmbed // embedded, hidden base driver.
+
+	// driver is the unregulated implementation backing baseEmbed, kept here
+	// so Snapshot/Restore/Close can reach it directly without going through
+	// the regulator.
+	driver *driver
 }
 
 var _ storagedriver.StorageDriver = &Driver{}
 
-// New constructs a new Driver.
-func New() *Driver {
+// resolveMaxThreads applies DriverParameters.MaxThreads' defaulting and
+// flooring rules: a zero value (including one left over from a caller's
+// DriverParameters literal that didn't set the field) means "unset" and
+// becomes defaultMaxThreads, and anything set below minThreads is raised to
+// it.
+func resolveMaxThreads(maxThreads uint64) uint64 {
+	if maxThreads == 0 {
+		maxThreads = defaultMaxThreads
+	}
+	if maxThreads < minThreads {
+		maxThreads = minThreads
+	}
+	return maxThreads
+}
+
+// New constructs a new Driver. Called with no arguments, it uses
+// defaultMaxThreads and no capacity limits, for backward compatibility;
+// callers that want eviction or a different concurrency limit should pass a
+// DriverParameters.
+func New(params ...DriverParameters) *Driver {
+	p := DriverParameters{MaxThreads: defaultMaxThreads}
+	if len(params) > 0 {
+		p = params[0]
+	}
+
+	p.MaxThreads = resolveMaxThreads(p.MaxThreads)
+
+	d := &driver{
+		root: &dir{
+			common: common{
+				p:   "/",
+				mod: time.Now(),
+			},
+		},
+		maxbytes:         p.MaxBytes,
+		maxentries:       p.MaxEntries,
+		lru:              newLRUList(),
+		inflight:         make(map[string]struct{}),
+		snapshotPath:     p.SnapshotPath,
+		snapshotInterval: p.SnapshotInterval,
+		redirectBaseURL:  strings.TrimSuffix(p.RedirectBaseURL, "/"),
+		redirectSecret:   []byte(p.RedirectSecret),
+	}
+
+	if d.snapshotPath != "" {
+		d.loadSnapshot()
+
+		if d.snapshotInterval > 0 {
+			d.stopSnapshot = make(chan struct{})
+			d.snapshotWG.Add(1)
+			go d.runSnapshotLoop()
+		}
+	}
+
 	return &Driver{
 		baseEmbed: baseEmbed{
 			Base: base.Base{
-				StorageDriver: &driver{
-					root: &dir{
-						common: common{
-							p:   "/",
-							mod: time.Now(),
-						},
-					},
-				},
+				StorageDriver: base.NewRegulator(d, p.MaxThreads),
 			},
 		},
+		driver: d,
 	}
 }
 
@@ -108,10 +334,31 @@ func (d *driver) PutContent(ctx context.Context, p string, contents []byte) erro
 		return fmt.Errorf("not a file")
 	}
 
+	before := int64(len(f.data))
 	f.truncate()
 	if _, err := f.WriteAt(contents, 0); err != nil {
 		return err
 	}
+	d.totalBytes += int64(len(f.data)) - before
+
+	// Guard the path just written against its own eviction: if contents
+	// alone push the driver over capacity and it's the only (or
+	// least-recently-used) tracked entry, evict would otherwise delete the
+	// blob PutContent is about to report success for.
+	// A path already held by an open writer stays guarded afterward.
+	d.inflightMu.Lock()
+	_, heldByWriter := d.inflight[normalized]
+	d.inflight[normalized] = struct{}{}
+	d.inflightMu.Unlock()
+
+	d.touch(normalized)
+	d.evict(span)
+
+	if !heldByWriter {
+		d.inflightMu.Lock()
+		delete(d.inflight, normalized)
+		d.inflightMu.Unlock()
+	}
 
 	return nil
 }
@@ -146,6 +393,8 @@ func (d *driver) reader(ctx context.Context, path string, offset int64) (io.Read
 		return nil, fmt.Errorf("%q is a directory", path)
 	}
 
+	d.touch(normalized)
+
 	return io.NopCloser(found.(*file).sectionReader(offset)), nil
 }
 
@@ -168,10 +417,16 @@ func (d *driver) Writer(ctx context.Context, path string, append bool) (storaged
 	}
 
 	if !append {
+		before := int64(len(f.data))
 		f.truncate()
+		d.totalBytes -= before
 	}
 
-	return d.newWriter(f), nil
+	d.inflightMu.Lock()
+	d.inflight[normalized] = struct{}{}
+	d.inflightMu.Unlock()
+
+	return d.newWriter(ctx, f, normalized), nil
 }
 
 // Stat returns info about the provided path.
@@ -190,6 +445,10 @@ func (d *driver) Stat(ctx context.Context, path string) (storagedriver.FileInfo,
 		return nil, storagedriver.PathNotFoundError{Path: path}
 	}
 
+	if !found.isdir() {
+		d.touch(normalized)
+	}
+
 	fi := storagedriver.FileInfoFields{
 		Path:    path,
 		IsDir:   found.isdir(),
@@ -249,10 +508,26 @@ func (d *driver) Move(ctx context.Context, sourcePath string, destPath string) e
 
 	normalizedSrc, normalizedDst := normalize(sourcePath), normalize(destPath)
 
+	found := d.root.find(normalizedSrc)
+	var moved []*file
+	var oldPaths []string
+	if found.path() == normalizedSrc {
+		moved = collectFiles(found)
+		for _, f := range moved {
+			oldPaths = append(oldPaths, f.path())
+		}
+	}
+
 	err := d.root.move(normalizedSrc, normalizedDst)
 	switch err {
 	case errNotExists:
 		return storagedriver.PathNotFoundError{Path: destPath}
+	case nil:
+		for i, f := range moved {
+			d.forget(oldPaths[i])
+			d.touch(f.path())
+		}
+		return nil
 	default:
 		return err
 	}
@@ -269,18 +544,40 @@ func (d *driver) Delete(ctx context.Context, path string) error {
 
 	normalized := normalize(path)
 
+	found := d.root.find(normalized)
+	var removed []*file
+	if found.path() == normalized {
+		removed = collectFiles(found)
+	}
+
 	err := d.root.delete(normalized)
 	switch err {
 	case errNotExists:
 		return storagedriver.PathNotFoundError{Path: path}
+	case nil:
+		for _, f := range removed {
+			d.totalBytes -= int64(len(f.data))
+			d.forget(f.path())
+		}
+		return nil
 	default:
 		return err
 	}
 }
 
-// RedirectURL returns a URL which may be used to retrieve the content stored at the given path.
-func (d *driver) RedirectURL(*http.Request, string) (string, error) {
-	return "", nil
+// RedirectURL returns a URL which may be used to retrieve the content stored
+// at the given path. It returns "" unless the driver was constructed with
+// both RedirectBaseURL and RedirectSecret, in which case it returns an
+// HMAC-signed, time-limited URL that Handler will serve the blob from.
+func (d *driver) RedirectURL(r *http.Request, path string) (string, error) {
+	if d.redirectBaseURL == "" || len(d.redirectSecret) == 0 {
+		return "", nil
+	}
+
+	normalized := normalize(path)
+	exp := time.Now().Add(redirectExpiry).Unix()
+
+	return fmt.Sprintf("%s%s%s?exp=%d&sig=%s", d.redirectBaseURL, redirectPathPrefix, escapePath(normalized), exp, d.sign(normalized, exp)), nil
 }
 
 // Walk traverses a filesystem defined within driver, starting
@@ -293,9 +590,85 @@ func (d *driver) Walk(ctx context.Context, path string, f storagedriver.WalkFn,
 	return storagedriver.WalkFallback(spanCtx, d, path, f, options...)
 }
 
+// touch records path as the most-recently-used entry in the driver's
+// eviction order. Only meaningful for file paths.
+func (d *driver) touch(path string) {
+	d.lruMu.Lock()
+	d.lru.touch(path)
+	d.lruMu.Unlock()
+}
+
+// forget removes path from the eviction order, e.g. after it has been
+// deleted, moved, or evicted.
+func (d *driver) forget(path string) {
+	d.lruMu.Lock()
+	d.lru.remove(path)
+	d.lruMu.Unlock()
+}
+
+// overCapacity reports whether the driver currently exceeds its configured
+// byte or entry limits.
+func (d *driver) overCapacity() bool {
+	d.lruMu.Lock()
+	entries := int64(d.lru.len())
+	d.lruMu.Unlock()
+
+	return (d.maxbytes > 0 && d.totalBytes > d.maxbytes) ||
+		(d.maxentries > 0 && entries > d.maxentries)
+}
+
+// evict removes least-recently-used blobs, skipping any with an open
+// uncommitted writer, until the driver is back within its configured
+// limits. The resulting cache pressure is reported as attributes on span,
+// which may be nil. Callers must hold d.mutex for writing.
+func (d *driver) evict(span trace.Span) {
+	var evictions, bytesEvicted int64
+
+	for d.overCapacity() {
+		d.inflightMu.Lock()
+		d.lruMu.Lock()
+		victim, ok := d.lru.evict(func(p string) bool {
+			_, busy := d.inflight[p]
+			return busy
+		})
+		d.lruMu.Unlock()
+		d.inflightMu.Unlock()
+
+		if !ok {
+			// Every remaining tracked blob is held open by an in-flight
+			// writer; nothing more can be safely evicted right now.
+			break
+		}
+
+		found := d.root.find(victim)
+		if found.path() != victim || found.isdir() {
+			continue
+		}
+
+		size := int64(len(found.(*file).data))
+		if err := d.root.delete(victim); err != nil {
+			continue
+		}
+
+		d.totalBytes -= size
+		evictions++
+		bytesEvicted += size
+	}
+
+	if span != nil {
+		span.SetAttributes(
+			attribute.Int64("evictions", evictions),
+			attribute.Int64("bytesEvicted", bytesEvicted),
+			attribute.Int64("currentBytes", d.totalBytes),
+		)
+	}
+}
+
 type writer struct {
 	d         *driver
+	ctx       context.Context
 	f         *file
+	path      string
 	buffer    []byte
 	buffSize  int
 	closed    bool
@@ -303,10 +676,12 @@ type writer struct {
 	cancelled bool
 }
 
-func (d *driver) newWriter(f *file) storagedriver.FileWriter {
+func (d *driver) newWriter(ctx context.Context, f *file, path string) storagedriver.FileWriter {
 	return &writer{
-		d: d,
-		f: f,
+		d:    d,
+		ctx:  ctx,
+		f:    f,
+		path: path,
 	}
 }
 
@@ -346,6 +721,7 @@ func (w *writer) Close() error {
 		return fmt.Errorf("already closed")
 	}
 	w.closed = true
+	defer w.release()
 
 	if err := w.flush(); err != nil {
 		return err
@@ -361,11 +737,19 @@ func (w *writer) Cancel(ctx context.Context) error {
 		return fmt.Errorf("already committed")
 	}
 	w.cancelled = true
+	defer w.release()
 
 	w.d.mutex.Lock()
 	defer w.d.mutex.Unlock()
 
-	return w.d.root.delete(w.f.path())
+	size := int64(len(w.f.data))
+	if err := w.d.root.delete(w.f.path()); err != nil {
+		return err
+	}
+	w.d.totalBytes -= size
+	w.d.forget(w.path)
+
+	return nil
 }
 
 func (w *writer) Commit(ctx context.Context) error {
@@ -377,6 +761,7 @@ func (w *writer) Commit(ctx context.Context) error {
 		return fmt.Errorf("already cancelled")
 	}
 	w.committed = true
+	defer w.release()
 
 	if err := w.flush(); err != nil {
 		return err
@@ -385,15 +770,31 @@ func (w *writer) Commit(ctx context.Context) error {
 	return nil
 }
 
+// release stops tracking w's path as an in-flight writer, making it
+// eligible for eviction again.
+func (w *writer) release() {
+	w.d.inflightMu.Lock()
+	delete(w.d.inflight, w.path)
+	w.d.inflightMu.Unlock()
+}
+
 func (w *writer) flush() error {
 	w.d.mutex.Lock()
 	defer w.d.mutex.Unlock()
 
+	before := int64(len(w.f.data))
 	if _, err := w.f.WriteAt(w.buffer, int64(len(w.f.data))); err != nil {
 		return err
 	}
+	w.d.totalBytes += int64(len(w.f.data)) - before
 	w.buffer = []byte{}
 	w.buffSize = 0
 
+	w.d.touch(w.path)
+
+	span, _ := tracing.StartSpan(w.ctx, fmt.Sprintf("%s:%s", driverName, "flush"))
+	defer tracing.StopSpan(span)
+	w.d.evict(span)
+
 	return nil
 }
@@ -0,0 +1,152 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+
+	storagedriver "github.com/distribution/distribution/v3/registry/storage/driver"
+)
+
+// TestPutContentUnderTightCapSurvives guards against the driver evicting the
+// blob it was just asked to store: with a single write one byte over the
+// cap, the write should still be readable back immediately afterward.
+func TestPutContentUnderTightCapSurvives(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{MaxBytes: 20})
+
+	contents := make([]byte, 21)
+	if err := d.PutContent(ctx, "/a", contents); err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+
+	got, err := d.GetContent(ctx, "/a")
+	if err != nil {
+		t.Fatalf("GetContent after PutContent under tight cap: %v", err)
+	}
+	if len(got) != len(contents) {
+		t.Fatalf("got %d bytes, want %d", len(got), len(contents))
+	}
+}
+
+// TestPutContentOverwriteAccounting checks that overwriting a path via
+// PutContent with smaller content subtracts the old size from the byte
+// total, so that putting a second small blob keeps the driver under its cap
+// and the first blob is not evicted.
+func TestPutContentOverwriteAccounting(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{MaxBytes: 10})
+
+	if err := d.PutContent(ctx, "/a", make([]byte, 10)); err != nil {
+		t.Fatalf("PutContent /a: %v", err)
+	}
+	// Overwrite /a with something smaller; if the pre-overwrite size were
+	// never subtracted, totalBytes would be stuck over cap even though the
+	// store now holds less data than the cap allows.
+	if err := d.PutContent(ctx, "/a", make([]byte, 2)); err != nil {
+		t.Fatalf("PutContent /a overwrite: %v", err)
+	}
+	if err := d.PutContent(ctx, "/b", make([]byte, 2)); err != nil {
+		t.Fatalf("PutContent /b: %v", err)
+	}
+
+	if _, err := d.GetContent(ctx, "/a"); err != nil {
+		t.Errorf("GetContent /a: %v (should not have been evicted, total content is well under cap)", err)
+	}
+	if _, err := d.GetContent(ctx, "/b"); err != nil {
+		t.Errorf("GetContent /b: %v", err)
+	}
+}
+
+// TestWriterTruncateAccounting checks that a non-append Writer that
+// discards existing content subtracts the old size from the driver's byte
+// total, rather than leaking it.
+func TestWriterTruncateAccounting(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{MaxBytes: 5})
+
+	if err := d.PutContent(ctx, "/a", make([]byte, 5)); err != nil {
+		t.Fatalf("PutContent /a: %v", err)
+	}
+
+	w, err := d.Writer(ctx, "/a", false)
+	if err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+	if err := w.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// /a is now empty; real usage is 0 bytes, well under cap. If the
+	// truncated 5 bytes were never subtracted from the byte total, the
+	// driver believes it's already at capacity and evicts /a unnecessarily
+	// as soon as anything else is written.
+	if err := d.PutContent(ctx, "/b", make([]byte, 5)); err != nil {
+		t.Fatalf("PutContent /b: %v", err)
+	}
+
+	if _, err := d.GetContent(ctx, "/a"); err != nil {
+		t.Errorf("GetContent /a: %v (truncated content should not have inflated the byte total)", err)
+	}
+	if _, err := d.GetContent(ctx, "/b"); err != nil {
+		t.Errorf("GetContent /b: %v", err)
+	}
+}
+
+// TestEvictionSkipsInFlightWriter checks that a blob with an open,
+// uncommitted writer is never evicted out from under it.
+func TestEvictionSkipsInFlightWriter(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{MaxEntries: 1})
+
+	if err := d.PutContent(ctx, "/a", []byte("hello")); err != nil {
+		t.Fatalf("PutContent /a: %v", err)
+	}
+
+	w, err := d.Writer(ctx, "/a", true)
+	if err != nil {
+		t.Fatalf("Writer: %v", err)
+	}
+	if _, err := w.Write([]byte("!")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// /a has an open writer; pushing the entry count over cap with /b must
+	// not evict /a while its writer is still open.
+	if err := d.PutContent(ctx, "/b", []byte("world")); err != nil {
+		t.Fatalf("PutContent /b: %v", err)
+	}
+	if _, err := d.GetContent(ctx, "/a"); err != nil {
+		t.Errorf("GetContent /a: %v (should not have been evicted while its writer was open)", err)
+	}
+
+	if err := w.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+}
+
+// TestEvictionReclaimsOldestEntry checks that once a path is no longer
+// guarded (committed, or never held open), exceeding the cap evicts the
+// least-recently-used entry.
+func TestEvictionReclaimsOldestEntry(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{MaxEntries: 1})
+
+	if err := d.PutContent(ctx, "/a", []byte("hello")); err != nil {
+		t.Fatalf("PutContent /a: %v", err)
+	}
+	if err := d.PutContent(ctx, "/b", []byte("world")); err != nil {
+		t.Fatalf("PutContent /b: %v", err)
+	}
+
+	if _, err := d.GetContent(ctx, "/a"); !isPathNotFound(err) {
+		t.Errorf("GetContent /a: got %v, want PathNotFoundError (should have been evicted for /b)", err)
+	}
+	if _, err := d.GetContent(ctx, "/b"); err != nil {
+		t.Errorf("GetContent /b: %v", err)
+	}
+}
+
+func isPathNotFound(err error) bool {
+	_, ok := err.(storagedriver.PathNotFoundError)
+	return ok
+}
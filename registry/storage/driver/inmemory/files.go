@@ -0,0 +1,298 @@
+package inmemory
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	errNotExists = errors.New("not exists")
+	errIsNotDir  = errors.New("not a directory")
+	errIsDir     = errors.New("is a directory")
+)
+
+// node is a member of the inmemory filesystem tree: either a *dir or a
+// *file.
+type node interface {
+	path() string
+	modtime() time.Time
+	isdir() bool
+}
+
+// common holds the fields shared by dir and file nodes.
+type common struct {
+	p   string
+	mod time.Time
+}
+
+func (c *common) path() string {
+	return c.p
+}
+
+func (c *common) modtime() time.Time {
+	return c.mod
+}
+
+// dir is a directory node in the inmemory filesystem tree.
+type dir struct {
+	common
+	children map[string]node
+}
+
+var _ node = (*dir)(nil)
+
+func (d *dir) isdir() bool {
+	return true
+}
+
+// mkdirs ensures that the directory hierarchy named by normalized exists,
+// creating directories as needed, and returns the deepest directory.
+func (d *dir) mkdirs(normalized string) (*dir, error) {
+	normalized = normalize(normalized)
+
+	current := d
+	for _, segment := range strings.Split(strings.Trim(normalized, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		if current.children == nil {
+			current.children = make(map[string]node)
+		}
+
+		child, ok := current.children[segment]
+		if !ok {
+			nd := &dir{
+				common: common{
+					p:   path.Join(current.p, segment),
+					mod: time.Now(),
+				},
+			}
+			current.children[segment] = nd
+			current = nd
+			continue
+		}
+
+		nd, ok := child.(*dir)
+		if !ok {
+			return nil, errIsNotDir
+		}
+		current = nd
+	}
+
+	return current, nil
+}
+
+// mkfile creates, if necessary, the file named by normalized, along with
+// any missing parent directories, and returns it.
+func (d *dir) mkfile(normalized string) (*file, error) {
+	normalized = normalize(normalized)
+
+	dirpath, filename := path.Split(normalized)
+
+	parent, err := d.mkdirs(dirpath)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, ok := parent.children[filename]; ok {
+		f, ok := existing.(*file)
+		if !ok {
+			return nil, errIsDir
+		}
+		return f, nil
+	}
+
+	if parent.children == nil {
+		parent.children = make(map[string]node)
+	}
+
+	f := &file{
+		common: common{
+			p:   normalized,
+			mod: time.Now(),
+		},
+	}
+
+	parent.children[filename] = f
+	parent.mod = time.Now()
+
+	return f, nil
+}
+
+// find locates the node at normalized, returning the deepest node found
+// along the path when the exact node does not exist.
+func (d *dir) find(normalized string) node {
+	normalized = normalize(normalized)
+
+	current := node(d)
+	for _, segment := range strings.Split(strings.Trim(normalized, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		nd, ok := current.(*dir)
+		if !ok {
+			return current
+		}
+
+		child, ok := nd.children[segment]
+		if !ok {
+			return current
+		}
+
+		current = child
+	}
+
+	return current
+}
+
+// list returns the paths of the direct descendants of this directory. It
+// returns errNotExists if this directory is not the one named by normalized.
+func (d *dir) list(normalized string) ([]string, error) {
+	if d.path() != normalize(normalized) {
+		return nil, errNotExists
+	}
+
+	entries := make([]string, 0, len(d.children))
+	for _, child := range d.children {
+		entries = append(entries, child.path())
+	}
+
+	sort.Strings(entries)
+
+	return entries, nil
+}
+
+// move relocates the node at normalizedSrc to normalizedDst, removing the
+// original.
+func (d *dir) move(normalizedSrc, normalizedDst string) error {
+	normalizedSrc = normalize(normalizedSrc)
+	normalizedDst = normalize(normalizedDst)
+
+	found := d.find(normalizedSrc)
+	if found.path() != normalizedSrc {
+		return errNotExists
+	}
+
+	srcDirpath, srcFilename := path.Split(normalizedSrc)
+	srcParent, ok := d.find(srcDirpath).(*dir)
+	if !ok {
+		return errNotExists
+	}
+
+	dstDirpath, dstFilename := path.Split(normalizedDst)
+	dstParent, err := d.mkdirs(dstDirpath)
+	if err != nil {
+		return err
+	}
+
+	delete(srcParent.children, srcFilename)
+
+	if dstParent.children == nil {
+		dstParent.children = make(map[string]node)
+	}
+	dstParent.children[dstFilename] = found
+
+	rename(found, normalizedSrc, normalizedDst)
+
+	return nil
+}
+
+// rename updates the path of n and, if n is a directory, every node in its
+// subtree, replacing the normalizedSrc prefix with normalizedDst.
+func rename(n node, normalizedSrc, normalizedDst string) {
+	switch n := n.(type) {
+	case *file:
+		n.p = normalizedDst
+	case *dir:
+		n.p = normalizedDst
+		for name, child := range n.children {
+			rename(child, path.Join(normalizedSrc, name), path.Join(normalizedDst, name))
+		}
+	}
+}
+
+// delete removes the node at normalized, along with any descendants.
+func (d *dir) delete(normalized string) error {
+	normalized = normalize(normalized)
+
+	dirpath, filename := path.Split(normalized)
+	parent, ok := d.find(dirpath).(*dir)
+	if !ok || parent.path() != normalize(dirpath) {
+		return errNotExists
+	}
+
+	if _, ok := parent.children[filename]; !ok {
+		return errNotExists
+	}
+
+	delete(parent.children, filename)
+
+	return nil
+}
+
+// collectFiles returns every *file node in the subtree rooted at n.
+func collectFiles(n node) []*file {
+	switch n := n.(type) {
+	case *file:
+		return []*file{n}
+	case *dir:
+		var files []*file
+		for _, child := range n.children {
+			files = append(files, collectFiles(child)...)
+		}
+		return files
+	default:
+		return nil
+	}
+}
+
+// file is a leaf node in the inmemory filesystem tree, holding blob
+// content.
+type file struct {
+	common
+	data []byte
+}
+
+var _ node = (*file)(nil)
+
+func (f *file) isdir() bool {
+	return false
+}
+
+func (f *file) truncate() {
+	f.data = f.data[:0]
+}
+
+func (f *file) sectionReader(offset int64) io.Reader {
+	return io.NewSectionReader(bytes.NewReader(f.data), offset, int64(len(f.data))-offset)
+}
+
+func (f *file) WriteAt(p []byte, offset int64) (int, error) {
+	if offset+int64(len(p)) > int64(cap(f.data)) {
+		data := make([]byte, len(f.data), offset+int64(len(p)))
+		copy(data, f.data)
+		f.data = data
+	}
+
+	if offset+int64(len(p)) > int64(len(f.data)) {
+		f.data = f.data[:offset+int64(len(p))]
+	}
+
+	n := copy(f.data[offset:offset+int64(len(p))], p)
+	f.mod = time.Now()
+
+	return n, nil
+}
+
+// normalize cleans p into a rooted, slash-separated path with no trailing
+// slash (except for the root itself).
+func normalize(p string) string {
+	return path.Clean("/" + p)
+}
@@ -0,0 +1,90 @@
+package inmemory
+
+// lruNode is an entry in an lruList's doubly-linked eviction order.
+type lruNode struct {
+	path       string
+	prev, next *lruNode
+}
+
+// lruList tracks blob paths in least-recently-used order. It is not safe
+// for concurrent use; callers must serialize access.
+type lruList struct {
+	entries    map[string]*lruNode
+	head, tail *lruNode // head is most-recently-used, tail is least.
+}
+
+func newLRUList() *lruList {
+	return &lruList{entries: make(map[string]*lruNode)}
+}
+
+// touch marks path as most-recently-used, adding it to the list if it is
+// not already tracked.
+func (l *lruList) touch(path string) {
+	if n, ok := l.entries[path]; ok {
+		l.unlink(n)
+		l.pushFront(n)
+		return
+	}
+
+	n := &lruNode{path: path}
+	l.entries[path] = n
+	l.pushFront(n)
+}
+
+// remove stops tracking path.
+func (l *lruList) remove(path string) {
+	n, ok := l.entries[path]
+	if !ok {
+		return
+	}
+
+	l.unlink(n)
+	delete(l.entries, path)
+}
+
+func (l *lruList) len() int {
+	return len(l.entries)
+}
+
+// evict removes and returns the least-recently-used path for which skip
+// returns false, searching from the least-recently-used end. It reports
+// false if every tracked path is skipped.
+func (l *lruList) evict(skip func(path string) bool) (string, bool) {
+	for n := l.tail; n != nil; n = n.prev {
+		if skip(n.path) {
+			continue
+		}
+
+		l.unlink(n)
+		delete(l.entries, n.path)
+		return n.path, true
+	}
+
+	return "", false
+}
+
+func (l *lruList) pushFront(n *lruNode) {
+	n.prev = nil
+	n.next = l.head
+	if l.head != nil {
+		l.head.prev = n
+	}
+	l.head = n
+	if l.tail == nil {
+		l.tail = n
+	}
+}
+
+func (l *lruList) unlink(n *lruNode) {
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		l.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		l.tail = n.prev
+	}
+	n.prev, n.next = nil, nil
+}
@@ -0,0 +1,44 @@
+package inmemory
+
+import "testing"
+
+// TestResolveMaxThreads checks that a zero MaxThreads (including one left
+// over from a DriverParameters literal that didn't set the field, such as
+// New(DriverParameters{SnapshotPath: "x"})) defaults to defaultMaxThreads
+// rather than being floored straight to minThreads.
+func TestResolveMaxThreads(t *testing.T) {
+	tests := []struct {
+		name string
+		in   uint64
+		want uint64
+	}{
+		{"zero defaults", 0, defaultMaxThreads},
+		{"below floor raised", 1, minThreads},
+		{"at floor unchanged", minThreads, minThreads},
+		{"above floor unchanged", minThreads + 50, minThreads + 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveMaxThreads(tt.in); got != tt.want {
+				t.Errorf("resolveMaxThreads(%d) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewWithPartialParameters checks that New, called with a
+// DriverParameters literal that sets unrelated fields but omits MaxThreads,
+// still constructs a driver and keeps the fields that were set.
+func TestNewWithPartialParameters(t *testing.T) {
+	d := New(DriverParameters{MaxBytes: 10})
+	if d.driver.maxbytes != 10 {
+		t.Fatalf("maxbytes = %d, want 10", d.driver.maxbytes)
+	}
+	// New doesn't expose the regulator's configured limit, so this only
+	// guards that construction with a partial literal still succeeds; the
+	// defaulting itself is covered directly by TestResolveMaxThreads.
+	if d == nil {
+		t.Fatal("New returned nil")
+	}
+}
@@ -0,0 +1,197 @@
+package inmemory
+
+import (
+	"context"
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotEntry is the on-disk representation of a single node in the
+// driver's tree, used by Snapshot/Restore.
+type snapshotEntry struct {
+	Path    string
+	IsDir   bool
+	ModTime time.Time
+	Data    []byte
+}
+
+// walkEntries appends a snapshotEntry for n, and for every node in its
+// subtree if n is a directory, to *out.
+func walkEntries(n node, out *[]snapshotEntry) {
+	switch n := n.(type) {
+	case *file:
+		*out = append(*out, snapshotEntry{Path: n.path(), ModTime: n.mod, Data: n.data})
+	case *dir:
+		*out = append(*out, snapshotEntry{Path: n.path(), IsDir: true, ModTime: n.mod})
+		for _, child := range n.children {
+			walkEntries(child, out)
+		}
+	}
+}
+
+func encodeSnapshot(root *dir, w io.Writer) error {
+	var entries []snapshotEntry
+	walkEntries(root, &entries)
+
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+func decodeSnapshot(r io.Reader) ([]snapshotEntry, error) {
+	var entries []snapshotEntry
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// snapshot serializes the current tree to w, holding only a read lock so
+// concurrent reads of the live tree are not blocked.
+func (d *driver) snapshot(w io.Writer) error {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return encodeSnapshot(d.root, w)
+}
+
+// restoreFrom replaces the driver's tree with the one serialized by a prior
+// call to snapshot.
+func (d *driver) restoreFrom(r io.Reader) error {
+	entries, err := decodeSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	root := &dir{common: common{p: "/", mod: time.Now()}}
+	lru := newLRUList()
+	var totalBytes int64
+
+	for _, e := range entries {
+		if e.IsDir {
+			nd, err := root.mkdirs(e.Path)
+			if err != nil {
+				return err
+			}
+			nd.mod = e.ModTime
+			continue
+		}
+
+		f, err := root.mkfile(e.Path)
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(e.Data, 0); err != nil {
+			return err
+		}
+		f.mod = e.ModTime
+		totalBytes += int64(len(e.Data))
+		lru.touch(e.Path)
+	}
+
+	d.mutex.Lock()
+	d.root = root
+	d.totalBytes = totalBytes
+	d.mutex.Unlock()
+
+	d.lruMu.Lock()
+	d.lru = lru
+	d.lruMu.Unlock()
+
+	return nil
+}
+
+// loadSnapshot attempts a best-effort load of snapshotPath into the driver.
+// A missing or unreadable file just leaves the driver empty, matching the
+// "attempt to load" contract of DriverParameters.SnapshotPath.
+func (d *driver) loadSnapshot() {
+	f, err := os.Open(d.snapshotPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_ = d.restoreFrom(f)
+}
+
+// flushSnapshot serializes the tree to a temp file alongside snapshotPath
+// and renames it into place, so a reader never observes a partial write.
+func (d *driver) flushSnapshot() error {
+	tmp, err := os.CreateTemp(filepath.Dir(d.snapshotPath), ".inmemory-snapshot-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	err = d.snapshot(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, d.snapshotPath)
+}
+
+// runSnapshotLoop periodically flushes a snapshot until stopSnapshot is
+// closed. It is only started when snapshotInterval is positive.
+func (d *driver) runSnapshotLoop() {
+	defer d.snapshotWG.Done()
+
+	ticker := time.NewTicker(d.snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.flushSnapshot()
+		case <-d.stopSnapshot:
+			return
+		}
+	}
+}
+
+// close stops the background snapshot loop, if running, and flushes a
+// final snapshot to snapshotPath. It is safe to call more than once;
+// only the first call does any work.
+func (d *driver) close() error {
+	var err error
+
+	d.closeOnce.Do(func() {
+		if d.stopSnapshot != nil {
+			close(d.stopSnapshot)
+			d.snapshotWG.Wait()
+		}
+
+		if d.snapshotPath == "" {
+			return
+		}
+
+		err = d.flushSnapshot()
+	})
+
+	return err
+}
+
+// Snapshot serializes the current contents of the Driver to w. It can be
+// used alongside Restore to persist and later reload the in-memory tree,
+// independently of the SnapshotPath/SnapshotInterval background mechanism.
+func (d *Driver) Snapshot(ctx context.Context, w io.Writer) error {
+	return d.driver.snapshot(w)
+}
+
+// Restore replaces the Driver's contents with the tree serialized by a
+// prior call to Snapshot.
+func (d *Driver) Restore(ctx context.Context, r io.Reader) error {
+	return d.driver.restoreFrom(r)
+}
+
+// Close stops the background snapshot goroutine, if any, and flushes a
+// final snapshot to SnapshotPath before returning.
+func (d *Driver) Close() error {
+	return d.driver.close()
+}
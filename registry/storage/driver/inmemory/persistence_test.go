@@ -0,0 +1,100 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSnapshotRestoreRoundTrip checks that a Snapshot taken from one Driver
+// can Restore into another, reproducing its contents.
+func TestSnapshotRestoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := New()
+
+	if err := src.PutContent(ctx, "/a/b", []byte("hello")); err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+	if err := src.PutContent(ctx, "/c", []byte("world")); err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(ctx, &buf); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	dst := New()
+	if err := dst.Restore(ctx, &buf); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	got, err := dst.GetContent(ctx, "/a/b")
+	if err != nil {
+		t.Fatalf("GetContent /a/b: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("GetContent /a/b = %q, want %q", got, "hello")
+	}
+
+	got, err = dst.GetContent(ctx, "/c")
+	if err != nil {
+		t.Fatalf("GetContent /c: %v", err)
+	}
+	if string(got) != "world" {
+		t.Errorf("GetContent /c = %q, want %q", got, "world")
+	}
+}
+
+// TestSnapshotPathLoadedOnNew checks that a Driver constructed with
+// SnapshotPath picks up a previously flushed snapshot at that path.
+func TestSnapshotPathLoadedOnNew(t *testing.T) {
+	ctx := context.Background()
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot")
+
+	first := New(DriverParameters{SnapshotPath: snapshotPath})
+	if err := first.PutContent(ctx, "/a", []byte("hello")); err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	second := New(DriverParameters{SnapshotPath: snapshotPath})
+	got, err := second.GetContent(ctx, "/a")
+	if err != nil {
+		t.Fatalf("GetContent /a: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("GetContent /a = %q, want %q", got, "hello")
+	}
+}
+
+// TestCloseIsIdempotent checks that a second Close on a Driver with a
+// background snapshot loop running doesn't panic closing stopSnapshot
+// twice.
+func TestCloseIsIdempotent(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot")
+	d := New(DriverParameters{SnapshotPath: snapshotPath, SnapshotInterval: time.Hour})
+
+	if err := d.Close(); err != nil {
+		t.Fatalf("first Close: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+// TestNewWithoutSnapshotPathDoesNotLoad checks that a Driver constructed
+// without SnapshotPath starts empty, regardless of any prior snapshot
+// files on disk.
+func TestNewWithoutSnapshotPathDoesNotLoad(t *testing.T) {
+	ctx := context.Background()
+	d := New()
+
+	if _, err := d.GetContent(ctx, "/a"); err == nil {
+		t.Fatalf("GetContent /a: expected error on fresh driver, got nil")
+	}
+}
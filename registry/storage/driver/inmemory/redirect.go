@@ -0,0 +1,97 @@
+package inmemory
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redirectPathPrefix is mounted by Handler and prefixed onto every URL
+// RedirectURL produces.
+const redirectPathPrefix = "/inmemory-blobs"
+
+// redirectExpiry bounds how long a URL returned by RedirectURL remains
+// valid.
+const redirectExpiry = 15 * time.Minute
+
+// sign computes the HMAC-SHA256 signature, hex-encoded, that Handler
+// requires for a request for path expiring at exp.
+func (d *driver) sign(path string, exp int64) string {
+	mac := hmac.New(sha256.New, d.redirectSecret)
+	fmt.Fprintf(mac, "%s:%d", path, exp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// escapePath percent-encodes each segment of a normalized path, leaving the
+// "/" separators intact, so it can be safely embedded in a URL.
+func escapePath(normalized string) string {
+	segments := strings.Split(normalized, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Handler returns an http.Handler that validates and serves the
+// HMAC-signed URLs produced by RedirectURL. Mount it at redirectPathPrefix
+// on the host named by RedirectBaseURL.
+func (d *Driver) Handler() http.Handler {
+	return http.HandlerFunc(d.driver.serveRedirect)
+}
+
+func (d *driver) serveRedirect(w http.ResponseWriter, r *http.Request) {
+	if len(d.redirectSecret) == 0 {
+		http.Error(w, "redirects not configured", http.StatusForbidden)
+		return
+	}
+
+	normalized := normalize(strings.TrimPrefix(r.URL.Path, redirectPathPrefix))
+
+	exp, err := strconv.ParseInt(r.URL.Query().Get("exp"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid or missing exp", http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() > exp {
+		http.Error(w, "url expired", http.StatusForbidden)
+		return
+	}
+	if !hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(d.sign(normalized, exp))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	// Copy the blob out while holding the lock, rather than handing
+	// http.ServeContent a reader over the live file: f.data can be
+	// truncated or overwritten in place by a concurrent PutContent/Writer
+	// for the whole, potentially long, duration of the HTTP response.
+	d.mutex.RLock()
+	found := d.root.find(normalized)
+	var data []byte
+	var modtime time.Time
+	if found.path() == normalized && !found.isdir() {
+		f := found.(*file)
+		data = make([]byte, len(f.data))
+		copy(data, f.data)
+		modtime = f.modtime()
+		// touch while still holding the lock that confirmed normalized
+		// exists: touching after RUnlock risks re-inserting an LRU entry
+		// for a path a concurrent Delete/Move/eviction has since removed.
+		d.touch(normalized)
+	}
+	d.mutex.RUnlock()
+
+	if data == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeContent(w, r, normalized, modtime, bytes.NewReader(data))
+}
@@ -0,0 +1,115 @@
+package inmemory
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestServeRedirectValidSignedURL(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{RedirectBaseURL: "http://example.com", RedirectSecret: "s3cret"})
+
+	if err := d.PutContent(ctx, "/a/b", []byte("hello")); err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+
+	url, err := d.RedirectURL(httptest.NewRequest(http.MethodGet, "/a/b", nil), "/a/b")
+	if err != nil {
+		t.Fatalf("RedirectURL: %v", err)
+	}
+	if url == "" {
+		t.Fatal("RedirectURL returned empty string with RedirectBaseURL/RedirectSecret set")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Body.String(); got != "hello" {
+		t.Errorf("body = %q, want %q", got, "hello")
+	}
+}
+
+func TestServeRedirectRejectsBadSignature(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{RedirectBaseURL: "http://example.com", RedirectSecret: "s3cret"})
+
+	if err := d.PutContent(ctx, "/a", []byte("hello")); err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+
+	url, err := d.RedirectURL(httptest.NewRequest(http.MethodGet, "/a", nil), "/a")
+	if err != nil {
+		t.Fatalf("RedirectURL: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, url+"tampered", nil)
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestServeRedirectRejectsExpiredURL(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{RedirectBaseURL: "http://example.com", RedirectSecret: "s3cret"})
+
+	if err := d.PutContent(ctx, "/a", []byte("hello")); err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+
+	normalized := "/a"
+	exp := time.Now().Add(-time.Minute).Unix()
+	url := "http://example.com" + redirectPathPrefix + escapePath(normalized) + "?exp=" + strconv.FormatInt(exp, 10) + "&sig=" + d.driver.sign(normalized, exp)
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestServeRedirectWithoutSecretConfigured(t *testing.T) {
+	d := New()
+
+	req := httptest.NewRequest(http.MethodGet, redirectPathPrefix+"/a", nil)
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestServeRedirectEscapesPathSegments(t *testing.T) {
+	ctx := context.Background()
+	d := New(DriverParameters{RedirectBaseURL: "http://example.com", RedirectSecret: "s3cret"})
+
+	if err := d.PutContent(ctx, "/a b/c", []byte("hello")); err != nil {
+		t.Fatalf("PutContent: %v", err)
+	}
+
+	url, err := d.RedirectURL(httptest.NewRequest(http.MethodGet, "/a b/c", nil), "/a b/c")
+	if err != nil {
+		t.Fatalf("RedirectURL: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+}